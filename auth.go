@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hariharan333/chatapp/store"
+)
+
+const sessionCookieName = "chat_session"
+const sessionTTL = 24 * time.Hour
+
+// sessionSigningKey signs the session tokens issued by /login. It is
+// generated fresh on every startup, which means a restart invalidates
+// any sessions issued before it.
+var sessionSigningKey = randomSessionKey()
+
+type contextKey string
+
+const accountIDContextKey contextKey = "account_id"
+
+// registerRequest is the JSON body accepted by /register.
+type registerRequest struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// registerHandler bcrypts the password and creates the account row.
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" || req.Username == "" || req.Password == "" {
+		http.Error(w, "id, username and password are required", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+
+	account, err := msgStore.CreateAccount(store.Account{
+		ID:           req.ID,
+		Username:     req.Username,
+		PasswordHash: string(hash),
+		Email:        req.Email,
+		AvatarURL:    req.AvatarURL,
+	})
+	if err != nil {
+		if err == store.ErrAccountExists {
+			http.Error(w, "Account already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(account)
+}
+
+// loginRequest is the JSON body accepted by /login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginHandler checks the password hash and issues a signed session
+// cookie in place of the old trust-the-query-param identity.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account, err := msgStore.GetAccountByUsername(req.Username)
+	if err != nil {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	token := signSession(account.ID)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(sessionTTL),
+	})
+
+	fmt.Fprintf(w, "Logged in as %s", account.Username)
+}
+
+// signSession returns an HMAC-signed "<accountID>.<expiry>.<sig>" token.
+func signSession(accountID string) string {
+	expiry := time.Now().Add(sessionTTL).Unix()
+	payload := fmt.Sprintf("%s.%d", accountID, expiry)
+	mac := hmac.New(sha256.New, sessionSigningKey)
+	mac.Write([]byte(payload))
+	sig := hex.EncodeToString(mac.Sum(nil))
+	return payload + "." + sig
+}
+
+// verifySession validates a session token and returns the account ID
+// it was issued for.
+func verifySession(token string) (string, error) {
+	parts := strings.SplitN(token, ".", 3)
+	if len(parts) != 3 {
+		return "", errors.New("malformed session token")
+	}
+	accountID, expiryStr, sig := parts[0], parts[1], parts[2]
+
+	mac := hmac.New(sha256.New, sessionSigningKey)
+	mac.Write([]byte(accountID + "." + expiryStr))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", errors.New("invalid session signature")
+	}
+
+	expiry, err := strconv.ParseInt(expiryStr, 10, 64)
+	if err != nil {
+		return "", errors.New("invalid session expiry")
+	}
+	if time.Now().Unix() > expiry {
+		return "", errors.New("session expired")
+	}
+
+	return accountID, nil
+}
+
+// withAuth resolves the caller from the session cookie and stores the
+// account ID in the request context, replacing the old ?id= query
+// param that let anyone impersonate any client.
+func withAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		accountID, err := verifySession(cookie.Value)
+		if err != nil {
+			http.Error(w, "Invalid or expired session", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), accountIDContextKey, accountID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// accountIDFromContext returns the authenticated caller's account ID,
+// as set by withAuth.
+func accountIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(accountIDContextKey).(string)
+	return id
+}
+
+// isAdmin reports whether clientID belongs to an account with the
+// "admin" role. Lookup failures are treated as non-admin.
+func isAdmin(clientID string) bool {
+	account, err := msgStore.GetAccount(clientID)
+	if err != nil {
+		return false
+	}
+	return account.Role == "admin"
+}
+
+func randomSessionKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal("Failed to generate session signing key:", err)
+	}
+	return key
+}
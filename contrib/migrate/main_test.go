@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hariharan333/chatapp/store"
+)
+
+func TestMigrateCopiesEveryMessageAcrossBatches(t *testing.T) {
+	const total = 1200
+	const batchSize = 500
+
+	src := mustOpenMemoryStore(t)
+	dst := mustOpenMemoryStore(t)
+
+	for i := 0; i < total; i++ {
+		if _, err := src.AddMessage("general", "alice", "hi"); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+
+	if err := migrate(src, dst, batchSize); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	got, err := dst.ListMessages(store.Filter{RoomID: "general"})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(got) != total {
+		t.Fatalf("migrated %d messages, want %d", len(got), total)
+	}
+}
+
+func TestMigratePreservesHiddenMessagesAndFidelity(t *testing.T) {
+	src := mustOpenMemoryStore(t)
+	dst := mustOpenMemoryStore(t)
+
+	visible, err := src.AddMessage("general", "alice", "hi")
+	if err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	hidden, err := src.AddMessage("general", "alice", "shh")
+	if err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	if err := src.HideMessage(hidden.ID); err != nil {
+		t.Fatalf("HideMessage: %v", err)
+	}
+
+	if err := migrate(src, dst, 500); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	got, err := dst.ListMessages(store.Filter{RoomID: "general", IncludeHidden: true})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("migrated %d messages, want 2 (hidden message was dropped)", len(got))
+	}
+
+	byContents := make(map[string]store.Message)
+	for _, msg := range got {
+		byContents[msg.Contents] = msg
+	}
+
+	v, ok := byContents["hi"]
+	if !ok {
+		t.Fatal("visible message missing after migrate")
+	}
+	if v.Timestamp != visible.Timestamp || !v.Visible {
+		t.Fatalf("visible message fidelity lost: got timestamp %q visible %v, want timestamp %q visible true", v.Timestamp, v.Visible, visible.Timestamp)
+	}
+
+	h, ok := byContents["shh"]
+	if !ok {
+		t.Fatal("hidden message missing after migrate")
+	}
+	if h.Timestamp != hidden.Timestamp || h.Visible {
+		t.Fatalf("hidden message fidelity lost: got timestamp %q visible %v, want timestamp %q visible false", h.Timestamp, h.Visible, hidden.Timestamp)
+	}
+}
+
+func mustOpenMemoryStore(t *testing.T) store.Store {
+	t.Helper()
+	s, err := store.Open("memory")
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	return s
+}
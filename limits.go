@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a classic per-client rate limiter: capacity tokens
+// refill continuously at refillRate tokens/sec, and allow() consumes
+// one token per call.
+type tokenBucket struct {
+	capacity   float64
+	refillRate float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(capacity, refillRate float64) *tokenBucket {
+	return &tokenBucket{
+		capacity:   capacity,
+		refillRate: refillRate,
+		tokens:     capacity,
+		lastRefill: time.Now(),
+	}
+}
+
+// allow reports whether a token is available, consuming it if so. When
+// denied, it also returns how long the caller should wait before the
+// next token is available.
+func (b *tokenBucket) allow() (bool, time.Duration) {
+	now := time.Now()
+	b.tokens += now.Sub(b.lastRefill).Seconds() * b.refillRate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.lastRefill = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.refillRate * float64(time.Second))
+	return false, wait
+}
+
+// rateLimiter tracks one tokenBucket per client ID, behind a single
+// mutex since buckets are cheap and requests are infrequent relative
+// to a chat server's other work.
+type rateLimiter struct {
+	mu         sync.Mutex
+	capacity   float64
+	refillRate float64
+	buckets    map[string]*clientBucket
+}
+
+type clientBucket struct {
+	bucket     *tokenBucket
+	lastAccess time.Time
+}
+
+// newRateLimiter builds a limiter allowing capacity actions per client,
+// refilling at refillRate tokens/sec (e.g. capacity/window.Seconds()).
+func newRateLimiter(capacity, refillRate float64) *rateLimiter {
+	return &rateLimiter{
+		capacity:   capacity,
+		refillRate: refillRate,
+		buckets:    make(map[string]*clientBucket),
+	}
+}
+
+func (l *rateLimiter) allow(clientID string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cb, ok := l.buckets[clientID]
+	if !ok {
+		cb = &clientBucket{bucket: newTokenBucket(l.capacity, l.refillRate)}
+		l.buckets[clientID] = cb
+	}
+	cb.lastAccess = time.Now()
+	return cb.bucket.allow()
+}
+
+// evictIdle drops buckets that haven't been touched in longer than
+// maxIdle, so clients who disconnect don't leak memory forever.
+func (l *rateLimiter) evictIdle(maxIdle time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := time.Now().Add(-maxIdle)
+	for clientID, cb := range l.buckets {
+		if cb.lastAccess.Before(cutoff) {
+			delete(l.buckets, clientID)
+		}
+	}
+}
+
+// startEvictionLoop runs evictIdle every interval for the life of the process.
+func (l *rateLimiter) startEvictionLoop(interval, maxIdle time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			l.evictIdle(maxIdle)
+		}
+	}()
+}
+
+// enforceRateLimit checks clientID against limiter, writing a 429
+// response with a Retry-After header when they're over quota. It
+// returns true when the caller should stop handling the request.
+func enforceRateLimit(w http.ResponseWriter, limiter *rateLimiter, clientID string) bool {
+	allowed, retryAfter := limiter.allow(clientID)
+	if allowed {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+1)))
+	http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+	return true
+}
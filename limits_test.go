@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllowsUpToCapacityThenDenies(t *testing.T) {
+	b := newTokenBucket(3, 1)
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := b.allow(); !allowed {
+			t.Fatalf("call %d: expected allowed", i)
+		}
+	}
+
+	allowed, wait := b.allow()
+	if allowed {
+		t.Fatal("expected denied once capacity is exhausted")
+	}
+	if wait <= 0 {
+		t.Fatalf("expected a positive retry-after, got %v", wait)
+	}
+}
+
+func TestRateLimiterEvictIdleDropsOldBuckets(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	l.allow("alice")
+
+	if _, ok := l.buckets["alice"]; !ok {
+		t.Fatal("expected alice's bucket to exist after allow")
+	}
+
+	l.evictIdle(0)
+	if _, ok := l.buckets["alice"]; ok {
+		t.Fatal("expected evictIdle(0) to drop alice's bucket immediately")
+	}
+}
+
+func TestRateLimiterKeepsRecentBuckets(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	l.allow("alice")
+
+	l.evictIdle(time.Minute)
+	if _, ok := l.buckets["alice"]; !ok {
+		t.Fatal("expected evictIdle(time.Minute) to keep a bucket touched just now")
+	}
+}
@@ -1,192 +1,234 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
-	"github.com/mattn/go-sqlite3"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/hariharan333/chatapp/store"
 )
 
+// generalRoomID is the default room the legacy /join, /send, /leave,
+// /messages, and /ws endpoints operate on. Clients that want their own
+// channel use the /rooms endpoints instead.
+const generalRoomID = "general"
+
 type Client struct {
 	ID      string
-	MsgChan chan string
+	MsgChan chan roomBroadcast
 }
 
-type ChatRoom struct {
+// Hub fans broadcasts out to only the clients subscribed to the
+// target room, rather than to every connected client.
+type Hub struct {
 	mu        sync.Mutex
-	clients   map[string]*Client
-	broadcast chan string
+	rooms     map[string]map[string]*Client // room ID -> client ID -> Client
+	broadcast chan roomBroadcast
+}
+
+// roomBroadcast is one event sent to every client subscribed to
+// RoomID. Event distinguishes a new message from an edit or a
+// hide/delete so clients can update their view accordingly.
+type roomBroadcast struct {
+	RoomID string        `json:"room_id"`
+	Event  string        `json:"event"`
+	Msg    store.Message `json:"message"`
 }
 
-var chatRoom = &ChatRoom{
-	clients:   make(map[string]*Client),
-	broadcast: make(chan string),
+const (
+	eventMessageCreated = "message.created"
+	eventMessageUpdated = "message.updated"
+	eventMessageDeleted = "message.deleted"
+)
+
+var hub = &Hub{
+	rooms:     make(map[string]map[string]*Client),
+	broadcast: make(chan roomBroadcast),
 }
 
-var db *sql.DB
+// msgStore is the active message store backend, selected at startup via -store.
+var msgStore store.Store
+
+var storeFlag = flag.String("store", "sqlite3:./chat.db", "message store backend: sqlite3:<path>, memory, or postgres://...")
+
+var (
+	messageRateLimit  = flag.Int("rate-messages", 5, "max chat messages per client per -rate-messages-window")
+	messageRateWindow = flag.Duration("rate-messages-window", 10*time.Second, "time window for -rate-messages")
+	joinRateLimit     = flag.Int("rate-joins", 10, "max join/leave actions per client per -rate-joins-window")
+	joinRateWindow    = flag.Duration("rate-joins-window", time.Minute, "time window for -rate-joins")
+)
+
+// messageLimiter and joinLimiter enforce per-client token-bucket rate
+// limits on sending messages and joining/leaving rooms, initialized in
+// main() once the flags above are parsed.
+var messageLimiter *rateLimiter
+var joinLimiter *rateLimiter
 
+const rateLimiterEvictionInterval = 5 * time.Minute
+const rateLimiterMaxIdle = 10 * time.Minute
 
-// Start the chat room for broadcasting messages
-func (c *ChatRoom) start() {
-	for {
-		msg := <-c.broadcast
-		c.mu.Lock()
-		for _, client := range c.clients {
+// start fans out broadcasts to the clients subscribed to each message's room.
+func (h *Hub) start() {
+	for rb := range h.broadcast {
+		h.mu.Lock()
+		for _, client := range h.rooms[rb.RoomID] {
 			select {
-			case client.MsgChan <- msg:
+			case client.MsgChan <- rb:
 			default:
 				// Avoid blocking, skip if the client's channel is full
 			}
 		}
-		c.mu.Unlock()
+		h.mu.Unlock()
+	}
+}
+
+// join registers client as listening to roomID's broadcasts.
+func (h *Hub) join(roomID string, client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.rooms[roomID] == nil {
+		h.rooms[roomID] = make(map[string]*Client)
 	}
+	h.rooms[roomID][client.ID] = client
+}
+
+// leave removes clientID from roomID's broadcast fan-out.
+func (h *Hub) leave(roomID, clientID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.rooms[roomID], clientID)
 }
 
-// Join the chat room after checking if the user exists in the database
+// leaveIfCurrent removes client from roomID's broadcast fan-out, but
+// only if client is still the registered entry for its ID. Used by
+// wsHandler's disconnect cleanup instead of leave: if the same client
+// ID has already reconnected with a new *Client by the time cleanup
+// runs, an unconditional delete would evict the newer connection's
+// live entry instead of being a no-op.
+func (h *Hub) leaveIfCurrent(roomID string, client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.rooms[roomID][client.ID] == client {
+		delete(h.rooms[roomID], client.ID)
+	}
+}
+
+// Join the chat room as the authenticated caller
 func joinChatHandler(w http.ResponseWriter, r *http.Request) {
-	clientID := r.URL.Query().Get("id")
-	if clientID == "" {
-		http.Error(w, "Client ID required", http.StatusBadRequest)
+	clientID := accountIDFromContext(r.Context())
+	if enforceRateLimit(w, joinLimiter, clientID) {
 		return
 	}
 
-	// Insert the new user into the database
-	insertUserQuery := `INSERT INTO users (id) VALUES (?)`
-
-	_, err := db.Exec(insertUserQuery, clientID)
-	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrConstraint {
-			// This error occurs if the ID already exists (due to the PRIMARY KEY constraint)
+	if err := msgStore.CreateUser(clientID); err != nil {
+		if err == store.ErrUserExists {
 			http.Error(w, "Client ID already exists", http.StatusConflict)
 			return
 		}
 		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
 		return
 	}
-
+	if err := msgStore.Subscribe(generalRoomID, clientID); err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
 
 	client := &Client{
 		ID:      clientID,
-		MsgChan: make(chan string, 10), // Buffered channel to avoid blocking
+		MsgChan: make(chan roomBroadcast, 10), // Buffered channel to avoid blocking
 	}
-
-	chatRoom.mu.Lock()
-	chatRoom.clients[clientID] = client
-	chatRoom.mu.Unlock()
+	hub.join(generalRoomID, client)
 
 	fmt.Fprintf(w, "Client %s joined the chat", clientID)
 }
 
-// Send a message to the chat room and store it in the DB
+// Send a message to the chat room and store it
 func sendMessageHandler(w http.ResponseWriter, r *http.Request) {
-	clientID := r.URL.Query().Get("id")
+	// Identity now comes from the SameSite=Lax session cookie rather
+	// than a query param, so a cross-site GET navigation would still
+	// carry it. Reject anything but POST so a plain <a>/<img> link
+	// can't post a message as the victim.
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientID := accountIDFromContext(r.Context())
+	if enforceRateLimit(w, messageLimiter, clientID) {
+		return
+	}
 	message := r.URL.Query().Get("message")
-	if clientID == "" || message == "" {
-		http.Error(w, "Client ID and message required", http.StatusBadRequest)
+	if message == "" {
+		http.Error(w, "Message required", http.StatusBadRequest)
 		return
 	}
 
-	// Check if the user exists in the database
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", clientID).Scan(&exists)
+	exists, err := msgStore.UserExists(clientID)
 	if err != nil || !exists {
 		http.Error(w, "Invalid client ID: Access Denied", http.StatusUnauthorized)
 		return
 	}
 
-	// Store the message in the database
-	_, err = db.Exec("INSERT INTO messages (client_id, message) VALUES (?, ?)", clientID, message)
+	msg, err := msgStore.AddMessage(generalRoomID, clientID, message)
 	if err != nil {
 		http.Error(w, "Failed to store message", http.StatusInternalServerError)
 		return
 	}
 
-	// Broadcast the message to all connected clients
-	chatRoom.broadcast <- fmt.Sprintf("%s: %s", clientID, message)
+	// Broadcast the message to clients subscribed to the general room
+	hub.broadcast <- roomBroadcast{RoomID: generalRoomID, Event: eventMessageCreated, Msg: msg}
 	fmt.Fprintf(w, "Message sent and stored")
 }
 
 // Leave the chat room
 func leaveChatHandler(w http.ResponseWriter, r *http.Request) {
-	clientID := r.URL.Query().Get("id")
-	if clientID == "" {
-		http.Error(w, "Client ID required", http.StatusBadRequest)
+	clientID := accountIDFromContext(r.Context())
+	if enforceRateLimit(w, joinLimiter, clientID) {
 		return
 	}
 
-	// Use Exec for DELETE queries
-	result, err := db.Exec("DELETE FROM users WHERE id = ?", clientID)
-	if err != nil {
+	if err := msgStore.DeleteUser(clientID); err != nil {
+		if err == store.ErrNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Database user deletion error: %v", err), http.StatusInternalServerError)
 		return
 	}
-
-	// Check how many rows were affected by the delete operation
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		http.Error(w, "Error retrieving rows affected", http.StatusInternalServerError)
-		return
-	}
-
-	if rowsAffected == 0 {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
-
-	fmt.Fprintf(w, "User %s successfully deleted", clientID)
-
-	chatRoom.mu.Lock()
-	if _, exists := chatRoom.clients[clientID]; exists {
-		delete(chatRoom.clients, clientID)
-	}
-	chatRoom.mu.Unlock()
+	msgStore.Unsubscribe(generalRoomID, clientID)
+	hub.leave(generalRoomID, clientID)
 
 	fmt.Fprintf(w, "Client %s left the chat", clientID)
 }
 
-// Get all messages from the database based on the insertion time
+// Get the general room's visible messages from the store ordered by
+// insertion time; an admin passing ?include_hidden=1 also gets hidden ones
 func getMessagesHandler(w http.ResponseWriter, r *http.Request) {
-	clientID := r.URL.Query().Get("id")
-	if clientID == "" {
-		http.Error(w, "Client ID required", http.StatusBadRequest)
-		return
-	}
+	clientID := accountIDFromContext(r.Context())
 
-	// Check if the user exists in the database
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", clientID).Scan(&exists)
+	exists, err := msgStore.UserExists(clientID)
 	if err != nil || !exists {
 		http.Error(w, "Invalid client ID: Access Denied", http.StatusUnauthorized)
 		return
 	}
 
-	// Fetch all messages from the database sorted by timestamp
-	rows, err := db.Query("SELECT client_id, message, timestamp FROM messages ORDER BY timestamp")
+	filter := store.Filter{RoomID: generalRoomID}
+	if r.URL.Query().Get("include_hidden") == "1" && isAdmin(clientID) {
+		filter.IncludeHidden = true
+	}
+
+	messages, err := msgStore.ListMessages(filter)
 	if err != nil {
 		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var messages []map[string]string
-	for rows.Next() {
-		var clientID, message, timestamp string
-		if err := rows.Scan(&clientID, &message, &timestamp); err != nil {
-			http.Error(w, "Failed to scan messages", http.StatusInternalServerError)
-			return
-		}
-		msg := map[string]string{
-			"client_id": clientID,
-			"message":   message,
-			"timestamp": timestamp,
-		}
-		messages = append(messages, msg)
-	}
 
 	// Return messages as JSON response
 	if err := json.NewEncoder(w).Encode(messages); err != nil {
@@ -194,43 +236,39 @@ func getMessagesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-
 func main() {
+	flag.Parse()
+
 	var err error
-	// Initialize SQLite database
-	db, err = sql.Open("sqlite3", "./chat.db")
+	msgStore, err = store.Open(*storeFlag)
 	if err != nil {
-		log.Fatal("Failed to open database:", err)
+		log.Fatal("Failed to open store:", err)
 	}
-	defer db.Close()
+	defer msgStore.Close()
 
-	// Create the users and messages table if they don't exist
-	createUsersTable := `CREATE TABLE IF NOT EXISTS users (
-		id TEXT PRIMARY KEY
-	);`
-	_, err = db.Exec(createUsersTable)
-	if err != nil {
-		log.Fatal("Failed to create users table:", err)
+	if _, err := msgStore.CreateRoom(store.Room{ID: generalRoomID, Name: generalRoomID, OwnerID: "system"}); err != nil && err != store.ErrRoomExists {
+		log.Fatal("Failed to create general room:", err)
 	}
 
-	createMessagesTable := `CREATE TABLE IF NOT EXISTS messages (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		client_id TEXT,
-		message TEXT,
-		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-	_, err = db.Exec(createMessagesTable)
-	if err != nil {
-		log.Fatal("Failed to create messages table:", err)
-	}
-
-	http.HandleFunc("/join", joinChatHandler)
-	http.HandleFunc("/send", sendMessageHandler)
-	http.HandleFunc("/leave", leaveChatHandler)
-	http.HandleFunc("/messages", getMessagesHandler)
-
-	go chatRoom.start()
+	messageLimiter = newRateLimiter(float64(*messageRateLimit), float64(*messageRateLimit)/messageRateWindow.Seconds())
+	messageLimiter.startEvictionLoop(rateLimiterEvictionInterval, rateLimiterMaxIdle)
+	joinLimiter = newRateLimiter(float64(*joinRateLimit), float64(*joinRateLimit)/joinRateWindow.Seconds())
+	joinLimiter.startEvictionLoop(rateLimiterEvictionInterval, rateLimiterMaxIdle)
+
+	http.HandleFunc("/register", registerHandler)
+	http.HandleFunc("/login", loginHandler)
+	http.HandleFunc("/join", withAuth(joinChatHandler))
+	http.HandleFunc("/send", withAuth(sendMessageHandler))
+	http.HandleFunc("/leave", withAuth(leaveChatHandler))
+	http.HandleFunc("/messages", withAuth(getMessagesHandler))
+	http.HandleFunc("/messages/", withAuth(messageByIDHandler))
+	http.HandleFunc("/ws", withAuth(wsHandler))
+	http.HandleFunc("/rooms", withAuth(roomsHandler))
+	http.HandleFunc("/rooms/", withAuth(roomActionHandler))
+	http.HandleFunc("/search", withAuth(searchHandler))
+
+	go hub.start()
 
 	log.Println("Chat server started on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
-}
\ No newline at end of file
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hariharan333/chatapp/store"
+)
+
+// editMessageRequest is the JSON body accepted by PATCH /messages/{id}.
+type editMessageRequest struct {
+	Contents string `json:"contents"`
+}
+
+// messageByIDHandler dispatches PATCH and DELETE requests against a
+// single message, with the message ID split out of the path by hand
+// since this module doesn't depend on a routing library.
+func messageByIDHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/messages/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		editMessageHandler(w, r, id)
+	case http.MethodDelete:
+		deleteMessageHandler(w, r, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// editMessageHandler updates a message's contents. Only the original
+// sender may edit it.
+func editMessageHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	clientID := accountIDFromContext(r.Context())
+
+	msg, err := msgStore.GetMessage(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			http.Error(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if msg.ClientID != clientID {
+		http.Error(w, "Only the original sender can edit this message", http.StatusForbidden)
+		return
+	}
+
+	var req editMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Contents == "" {
+		http.Error(w, "contents is required", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := msgStore.UpdateMessageContents(id, req.Contents)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	hub.broadcast <- roomBroadcast{RoomID: updated.RoomID, Event: eventMessageUpdated, Msg: updated}
+	json.NewEncoder(w).Encode(updated)
+}
+
+// deleteMessageHandler hides a message rather than hard-deleting it.
+// Only the original sender or an admin may hide a message.
+func deleteMessageHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	clientID := accountIDFromContext(r.Context())
+
+	msg, err := msgStore.GetMessage(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			http.Error(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if msg.ClientID != clientID && !isAdmin(clientID) {
+		http.Error(w, "Only the sender or an admin can delete this message", http.StatusForbidden)
+		return
+	}
+
+	if err := msgStore.HideMessage(id); err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	msg.Visible = false
+	hub.broadcast <- roomBroadcast{RoomID: msg.RoomID, Event: eventMessageDeleted, Msg: msg}
+	fmt.Fprintf(w, "Message %d deleted", id)
+}
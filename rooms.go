@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hariharan333/chatapp/store"
+)
+
+// createRoomRequest is the JSON body accepted by POST /rooms.
+type createRoomRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// roomsHandler creates (POST) or lists (GET) rooms.
+func roomsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req createRoomRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.ID == "" || req.Name == "" {
+			http.Error(w, "id and name are required", http.StatusBadRequest)
+			return
+		}
+
+		ownerID := accountIDFromContext(r.Context())
+		room, err := msgStore.CreateRoom(store.Room{ID: req.ID, Name: req.Name, OwnerID: ownerID})
+		if err != nil {
+			if err == store.ErrRoomExists {
+				http.Error(w, "Room already exists", http.StatusConflict)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(room)
+
+	case http.MethodGet:
+		rooms, err := msgStore.ListRooms()
+		if err != nil {
+			http.Error(w, "Failed to list rooms", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(rooms)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// roomActionHandler dispatches /rooms/{id}/join, /leave, /send, and
+// /messages. The room ID and action are split out of the path by hand
+// since this module doesn't depend on a routing library.
+func roomActionHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/rooms/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, "Expected /rooms/{id}/{join|leave|send|messages}", http.StatusNotFound)
+		return
+	}
+	roomID, action := parts[0], parts[1]
+
+	if _, err := msgStore.GetRoom(roomID); err != nil {
+		if err == store.ErrNotFound {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	switch action {
+	case "join":
+		roomJoinHandler(w, r, roomID)
+	case "leave":
+		roomLeaveHandler(w, r, roomID)
+	case "send":
+		roomSendHandler(w, r, roomID)
+	case "messages":
+		roomMessagesHandler(w, r, roomID)
+	default:
+		http.Error(w, "Unknown room action", http.StatusNotFound)
+	}
+}
+
+// roomJoinHandler subscribes the caller to roomID. It only records the
+// subscription in the store; it does not register a hub entry, since
+// real-time fan-out requires an actual reader for the broadcast
+// channel. Clients that want live updates connect to /ws?room=<id>
+// instead, which joins the hub itself.
+func roomJoinHandler(w http.ResponseWriter, r *http.Request, roomID string) {
+	clientID := accountIDFromContext(r.Context())
+	if enforceRateLimit(w, joinLimiter, clientID) {
+		return
+	}
+
+	if err := msgStore.Subscribe(roomID, clientID); err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprintf(w, "Client %s joined room %s", clientID, roomID)
+}
+
+func roomLeaveHandler(w http.ResponseWriter, r *http.Request, roomID string) {
+	clientID := accountIDFromContext(r.Context())
+	if enforceRateLimit(w, joinLimiter, clientID) {
+		return
+	}
+
+	if err := msgStore.Unsubscribe(roomID, clientID); err != nil {
+		if err == store.ErrNotFound {
+			http.Error(w, "Not subscribed to room", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	hub.leave(roomID, clientID)
+
+	fmt.Fprintf(w, "Client %s left room %s", clientID, roomID)
+}
+
+func roomSendHandler(w http.ResponseWriter, r *http.Request, roomID string) {
+	// See sendMessageHandler: the session cookie is SameSite=Lax, so a
+	// GET-reachable send would be forgeable from a cross-site link.
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientID := accountIDFromContext(r.Context())
+	if enforceRateLimit(w, messageLimiter, clientID) {
+		return
+	}
+	message := r.URL.Query().Get("message")
+	if message == "" {
+		http.Error(w, "Message required", http.StatusBadRequest)
+		return
+	}
+
+	subscribed, err := msgStore.IsSubscribed(roomID, clientID)
+	if err != nil || !subscribed {
+		http.Error(w, "Not subscribed to room", http.StatusForbidden)
+		return
+	}
+
+	msg, err := msgStore.AddMessage(roomID, clientID, message)
+	if err != nil {
+		http.Error(w, "Failed to store message", http.StatusInternalServerError)
+		return
+	}
+
+	hub.broadcast <- roomBroadcast{RoomID: roomID, Event: eventMessageCreated, Msg: msg}
+	fmt.Fprintf(w, "Message sent and stored")
+}
+
+func roomMessagesHandler(w http.ResponseWriter, r *http.Request, roomID string) {
+	clientID := accountIDFromContext(r.Context())
+
+	subscribed, err := msgStore.IsSubscribed(roomID, clientID)
+	if err != nil || !subscribed {
+		http.Error(w, "Not subscribed to room", http.StatusForbidden)
+		return
+	}
+
+	filter := store.Filter{RoomID: roomID, Limit: 50}
+	if beforeID := r.URL.Query().Get("before_id"); beforeID != "" {
+		if parsed, err := strconv.ParseInt(beforeID, 10, 64); err == nil {
+			filter.BeforeID = parsed
+		}
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if parsed, err := strconv.Atoi(limit); err == nil && parsed > 0 {
+			filter.Limit = parsed
+		}
+	}
+	if r.URL.Query().Get("include_hidden") == "1" && isAdmin(clientID) {
+		filter.IncludeHidden = true
+	}
+
+	messages, err := msgStore.ListMessages(filter)
+	if err != nil {
+		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(messages); err != nil {
+		http.Error(w, "Failed to encode messages", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hariharan333/chatapp/store"
+)
+
+// searchHandler serves /search?q=&room_id=&client_id=&from=&to=&offset=&limit=,
+// backed by Store.Search (SQLite FTS5, where available).
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	filter := store.SearchFilter{
+		Query:    q,
+		RoomID:   r.URL.Query().Get("room_id"),
+		ClientID: r.URL.Query().Get("client_id"),
+		From:     r.URL.Query().Get("from"),
+		To:       r.URL.Query().Get("to"),
+		Limit:    50,
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if parsed, err := strconv.Atoi(limit); err == nil && parsed > 0 {
+			filter.Limit = parsed
+		}
+	}
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		if parsed, err := strconv.Atoi(offset); err == nil && parsed >= 0 {
+			filter.Offset = parsed
+		}
+	}
+
+	results, err := msgStore.Search(filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		http.Error(w, "Failed to encode results", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,361 @@
+package store
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore is an in-process Store with no persistence,
+// intended for tests and local development (-store=memory).
+type memoryStore struct {
+	mu            sync.Mutex
+	users         map[string]bool
+	messages      []Message
+	nextID        int64
+	accounts      map[string]Account         // keyed by username
+	rooms         map[string]Room            // keyed by id
+	roomNames     map[string]bool            // taken room names
+	subscriptions map[string]map[string]bool // room id -> client id -> subscribed
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users:         make(map[string]bool),
+		accounts:      make(map[string]Account),
+		rooms:         make(map[string]Room),
+		roomNames:     make(map[string]bool),
+		subscriptions: make(map[string]map[string]bool),
+	}
+}
+
+func (s *memoryStore) CreateUser(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.users[id] {
+		return ErrUserExists
+	}
+	s.users[id] = true
+	return nil
+}
+
+func (s *memoryStore) DeleteUser(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.users[id] {
+		return ErrNotFound
+	}
+	delete(s.users, id)
+	return nil
+}
+
+func (s *memoryStore) UserExists(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[id], nil
+}
+
+func (s *memoryStore) AddMessage(roomID, clientID, contents string) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	msg := Message{
+		ID:        s.nextID,
+		RoomID:    roomID,
+		ClientID:  clientID,
+		Contents:  contents,
+		Timestamp: time.Now().UTC().Format("2006-01-02 15:04:05"),
+		Visible:   true,
+	}
+	s.messages = append(s.messages, msg)
+	return msg, nil
+}
+
+func (s *memoryStore) InsertMessage(msg Message) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	msg.ID = s.nextID
+	s.messages = append(s.messages, msg)
+	return msg, nil
+}
+
+func (s *memoryStore) ListMessages(filter Filter) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := func(msg Message) bool {
+		if filter.RoomID != "" && msg.RoomID != filter.RoomID {
+			return false
+		}
+		if filter.Before != "" && msg.Timestamp >= filter.Before {
+			return false
+		}
+		if filter.After != "" && msg.Timestamp <= filter.After {
+			return false
+		}
+		if filter.BeforeID > 0 && msg.ID >= filter.BeforeID {
+			return false
+		}
+		if filter.AfterID > 0 && msg.ID <= filter.AfterID {
+			return false
+		}
+		if !filter.IncludeHidden && !msg.Visible {
+			return false
+		}
+		return true
+	}
+
+	var matched []Message
+	if ascendingPagination(filter) {
+		for i := 0; i < len(s.messages); i++ {
+			msg := s.messages[i]
+			if !matches(msg) {
+				continue
+			}
+			matched = append(matched, msg)
+			if filter.Limit > 0 && len(matched) >= filter.Limit {
+				break
+			}
+		}
+		return matched, nil
+	}
+
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		msg := s.messages[i]
+		if !matches(msg) {
+			continue
+		}
+		matched = append(matched, msg)
+		if filter.Limit > 0 && len(matched) >= filter.Limit {
+			break
+		}
+	}
+
+	reverseMessages(matched)
+	return matched, nil
+}
+
+// Search does a case-insensitive substring scan, newest first, since
+// the in-memory backend has no full-text index of its own.
+func (s *memoryStore) Search(filter SearchFilter) ([]SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lowerQuery := strings.ToLower(filter.Query)
+	var matched []SearchResult
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		msg := s.messages[i]
+		if !msg.Visible {
+			continue
+		}
+		if filter.RoomID != "" && msg.RoomID != filter.RoomID {
+			continue
+		}
+		if filter.ClientID != "" && msg.ClientID != filter.ClientID {
+			continue
+		}
+		if filter.From != "" && msg.Timestamp < filter.From {
+			continue
+		}
+		if filter.To != "" && msg.Timestamp > filter.To {
+			continue
+		}
+		idx := strings.Index(strings.ToLower(msg.Contents), lowerQuery)
+		if idx < 0 {
+			continue
+		}
+		matched = append(matched, SearchResult{
+			ID:        msg.ID,
+			RoomID:    msg.RoomID,
+			ClientID:  msg.ClientID,
+			Contents:  msg.Contents,
+			Timestamp: msg.Timestamp,
+			Snippet:   snippetAround(msg.Contents, idx, len(filter.Query)),
+		})
+	}
+
+	if filter.Offset > 0 {
+		if filter.Offset >= len(matched) {
+			return nil, nil
+		}
+		matched = matched[filter.Offset:]
+	}
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
+func (s *memoryStore) GetMessage(id int64) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, msg := range s.messages {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return Message{}, ErrNotFound
+}
+
+func (s *memoryStore) UpdateMessageContents(id int64, contents string) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, msg := range s.messages {
+		if msg.ID == id {
+			s.messages[i].Contents = contents
+			return s.messages[i], nil
+		}
+	}
+	return Message{}, ErrNotFound
+}
+
+func (s *memoryStore) HideMessage(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, msg := range s.messages {
+		if msg.ID == id {
+			s.messages[i].Visible = false
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryStore) DeleteMessage(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, msg := range s.messages {
+		if msg.ID == id {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryStore) CreateAccount(account Account) (Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.accounts[account.Username]; exists {
+		return Account{}, ErrAccountExists
+	}
+	if account.Role == "" {
+		account.Role = "user"
+	}
+	account.CreatedAt = time.Now().UTC().Format("2006-01-02 15:04:05")
+	s.accounts[account.Username] = account
+	return account, nil
+}
+
+func (s *memoryStore) GetAccount(id string) (Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, account := range s.accounts {
+		if account.ID == id {
+			return account, nil
+		}
+	}
+	return Account{}, ErrNotFound
+}
+
+func (s *memoryStore) GetAccountByUsername(username string) (Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, exists := s.accounts[username]
+	if !exists {
+		return Account{}, ErrNotFound
+	}
+	return account, nil
+}
+
+func (s *memoryStore) CreateRoom(room Room) (Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomNames[room.Name] {
+		return Room{}, ErrRoomExists
+	}
+	room.CreatedAt = time.Now().UTC().Format("2006-01-02 15:04:05")
+	s.rooms[room.ID] = room
+	s.roomNames[room.Name] = true
+	return room, nil
+}
+
+func (s *memoryStore) GetRoom(id string) (Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, exists := s.rooms[id]
+	if !exists {
+		return Room{}, ErrNotFound
+	}
+	return room, nil
+}
+
+func (s *memoryStore) ListRooms() ([]Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rooms := make([]Room, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func (s *memoryStore) Subscribe(roomID, clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscriptions[roomID] == nil {
+		s.subscriptions[roomID] = make(map[string]bool)
+	}
+	s.subscriptions[roomID][clientID] = true
+	return nil
+}
+
+func (s *memoryStore) Unsubscribe(roomID, clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.subscriptions[roomID][clientID] {
+		return ErrNotFound
+	}
+	delete(s.subscriptions[roomID], clientID)
+	return nil
+}
+
+func (s *memoryStore) IsSubscribed(roomID, clientID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.subscriptions[roomID][clientID], nil
+}
+
+func (s *memoryStore) ListSubscribers(roomID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var clientIDs []string
+	for clientID := range s.subscriptions[roomID] {
+		clientIDs = append(clientIDs, clientID)
+	}
+	return clientIDs, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
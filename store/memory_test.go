@@ -0,0 +1,75 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+// timestampForTest returns a strictly increasing timestamp string for
+// seq, in the same lexically-sortable format the stores use, without
+// depending on wall-clock resolution.
+func timestampForTest(seq int64) string {
+	return fmt.Sprintf("2026-01-01 00:00:%02d", seq)
+}
+
+func TestMemoryStoreListMessagesAscendingPagination(t *testing.T) {
+	s := newMemoryStore()
+	// AddMessage timestamps have only second resolution, so build the
+	// fixture directly with distinct timestamps rather than racing the
+	// clock across five inserts.
+	for i := int64(1); i <= 5; i++ {
+		s.messages = append(s.messages, Message{
+			ID:        i,
+			RoomID:    "general",
+			ClientID:  "alice",
+			Contents:  "hi",
+			Timestamp: timestampForTest(i),
+			Visible:   true,
+		})
+	}
+	s.nextID = 5
+
+	all, err := s.ListMessages(Filter{RoomID: "general"})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("got %d messages, want 5", len(all))
+	}
+
+	// Paging forward with After+Ascending+Limit should walk the oldest
+	// unmigrated rows, not keep returning the newest Limit rows.
+	page, err := s.ListMessages(Filter{RoomID: "general", After: all[0].Timestamp, Ascending: true, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(page) != 2 {
+		t.Fatalf("got %d messages, want 2", len(page))
+	}
+	if page[0].ID != all[1].ID || page[1].ID != all[2].ID {
+		t.Fatalf("got ids %d,%d, want %d,%d", page[0].ID, page[1].ID, all[1].ID, all[2].ID)
+	}
+}
+
+func TestMemoryStoreListMessagesDefaultOrderUnchanged(t *testing.T) {
+	s := newMemoryStore()
+	for i := 0; i < 3; i++ {
+		if _, err := s.AddMessage("general", "alice", "hi"); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+
+	messages, err := s.ListMessages(Filter{RoomID: "general", Limit: 2})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	if messages[0].ID >= messages[1].ID {
+		t.Fatalf("expected chronological order, got ids %d,%d", messages[0].ID, messages[1].ID)
+	}
+	if messages[1].ID != 3 {
+		t.Fatalf("Before/After-less Limit should keep the newest rows, got latest id %d, want 3", messages[1].ID)
+	}
+}
@@ -0,0 +1,469 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresStore is a Store backed by PostgreSQL, selected with
+// a "postgres://" or "postgresql://" -store DSN.
+type postgresStore struct {
+	db *sql.DB
+}
+
+func newPostgresStore(dsn string) (*postgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &postgresStore{db: db}
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *postgresStore) init() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS users (
+			id TEXT PRIMARY KEY
+		);`,
+		`CREATE TABLE IF NOT EXISTS messages (
+			id BIGSERIAL PRIMARY KEY,
+			room_id TEXT,
+			client_id TEXT,
+			message TEXT,
+			timestamp TIMESTAMPTZ DEFAULT now(),
+			visible BOOLEAN DEFAULT true
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages (timestamp);`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_room_id ON messages (room_id);`,
+		`CREATE TABLE IF NOT EXISTS accounts (
+			id TEXT PRIMARY KEY,
+			username TEXT UNIQUE,
+			password_hash TEXT,
+			email TEXT,
+			avatar_url TEXT,
+			role TEXT DEFAULT 'user',
+			created_at TIMESTAMPTZ DEFAULT now()
+		);`,
+		`CREATE TABLE IF NOT EXISTS rooms (
+			id TEXT PRIMARY KEY,
+			name TEXT UNIQUE,
+			owner_id TEXT,
+			created_at TIMESTAMPTZ DEFAULT now()
+		);`,
+		`CREATE TABLE IF NOT EXISTS subscriptions (
+			room_id TEXT,
+			client_id TEXT,
+			joined_at TIMESTAMPTZ DEFAULT now(),
+			PRIMARY KEY (room_id, client_id)
+		);`,
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *postgresStore) CreateUser(id string) error {
+	_, err := s.db.Exec(`INSERT INTO users (id) VALUES ($1)`, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return ErrUserExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *postgresStore) DeleteUser(id string) error {
+	result, err := s.db.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) UserExists(id string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, id).Scan(&exists)
+	return exists, err
+}
+
+func (s *postgresStore) AddMessage(roomID, clientID, contents string) (Message, error) {
+	msg := Message{RoomID: roomID, ClientID: clientID, Contents: contents}
+	err := s.db.QueryRow(
+		`INSERT INTO messages (room_id, client_id, message) VALUES ($1, $2, $3) RETURNING id, timestamp::text, visible`,
+		roomID, clientID, contents,
+	).Scan(&msg.ID, &msg.Timestamp, &msg.Visible)
+	if err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func (s *postgresStore) InsertMessage(msg Message) (Message, error) {
+	err := s.db.QueryRow(
+		`INSERT INTO messages (room_id, client_id, message, timestamp, visible) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		msg.RoomID, msg.ClientID, msg.Contents, msg.Timestamp, msg.Visible,
+	).Scan(&msg.ID)
+	if err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func (s *postgresStore) ListMessages(filter Filter) ([]Message, error) {
+	query := `SELECT id, room_id, client_id, message, timestamp::text, visible FROM messages WHERE 1=1`
+	var args []interface{}
+	next := 1
+
+	if filter.RoomID != "" {
+		query += fmt.Sprintf(" AND room_id = $%d", next)
+		args = append(args, filter.RoomID)
+		next++
+	}
+	if filter.Before != "" {
+		query += fmt.Sprintf(" AND timestamp < $%d", next)
+		args = append(args, filter.Before)
+		next++
+	}
+	if filter.After != "" {
+		query += fmt.Sprintf(" AND timestamp > $%d", next)
+		args = append(args, filter.After)
+		next++
+	}
+	if filter.BeforeID > 0 {
+		query += fmt.Sprintf(" AND id < $%d", next)
+		args = append(args, filter.BeforeID)
+		next++
+	}
+	if filter.AfterID > 0 {
+		query += fmt.Sprintf(" AND id > $%d", next)
+		args = append(args, filter.AfterID)
+		next++
+	}
+	if !filter.IncludeHidden {
+		query += " AND visible = true"
+	}
+	ascending := ascendingPagination(filter)
+	if ascending {
+		query += " ORDER BY timestamp ASC, id ASC"
+	} else {
+		query += " ORDER BY timestamp DESC, id DESC"
+	}
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", next)
+		args = append(args, filter.Limit)
+		next++
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.RoomID, &msg.ClientID, &msg.Contents, &msg.Timestamp, &msg.Visible); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !ascending {
+		reverseMessages(messages)
+	}
+	return messages, nil
+}
+
+// Search matches filter.Query with a case-insensitive ILIKE scan and
+// builds a snippet by hand, since this backend doesn't maintain a
+// tsvector index of its own.
+func (s *postgresStore) Search(filter SearchFilter) ([]SearchResult, error) {
+	query := `SELECT id, room_id, client_id, message, timestamp::text FROM messages WHERE message ILIKE $1 AND visible = true`
+	args := []interface{}{"%" + filter.Query + "%"}
+	next := 2
+
+	if filter.RoomID != "" {
+		query += fmt.Sprintf(" AND room_id = $%d", next)
+		args = append(args, filter.RoomID)
+		next++
+	}
+	if filter.ClientID != "" {
+		query += fmt.Sprintf(" AND client_id = $%d", next)
+		args = append(args, filter.ClientID)
+		next++
+	}
+	if filter.From != "" {
+		query += fmt.Sprintf(" AND timestamp >= $%d", next)
+		args = append(args, filter.From)
+		next++
+	}
+	if filter.To != "" {
+		query += fmt.Sprintf(" AND timestamp <= $%d", next)
+		args = append(args, filter.To)
+		next++
+	}
+	query += " ORDER BY timestamp DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", next)
+		args = append(args, filter.Limit)
+		next++
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", next)
+		args = append(args, filter.Offset)
+		next++
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lowerQuery := strings.ToLower(filter.Query)
+	var results []SearchResult
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.RoomID, &msg.ClientID, &msg.Contents, &msg.Timestamp); err != nil {
+			return nil, err
+		}
+		idx := strings.Index(strings.ToLower(msg.Contents), lowerQuery)
+		results = append(results, SearchResult{
+			ID:        msg.ID,
+			RoomID:    msg.RoomID,
+			ClientID:  msg.ClientID,
+			Contents:  msg.Contents,
+			Timestamp: msg.Timestamp,
+			Snippet:   snippetAround(msg.Contents, idx, len(filter.Query)),
+		})
+	}
+	return results, rows.Err()
+}
+
+func (s *postgresStore) GetMessage(id int64) (Message, error) {
+	var msg Message
+	err := s.db.QueryRow(
+		`SELECT id, room_id, client_id, message, timestamp::text, visible FROM messages WHERE id = $1`, id,
+	).Scan(&msg.ID, &msg.RoomID, &msg.ClientID, &msg.Contents, &msg.Timestamp, &msg.Visible)
+	if err == sql.ErrNoRows {
+		return Message{}, ErrNotFound
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func (s *postgresStore) UpdateMessageContents(id int64, contents string) (Message, error) {
+	result, err := s.db.Exec(`UPDATE messages SET message = $1 WHERE id = $2`, contents, id)
+	if err != nil {
+		return Message{}, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Message{}, err
+	}
+	if rowsAffected == 0 {
+		return Message{}, ErrNotFound
+	}
+	return s.GetMessage(id)
+}
+
+func (s *postgresStore) HideMessage(id int64) error {
+	result, err := s.db.Exec(`UPDATE messages SET visible = false WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) DeleteMessage(id int64) error {
+	result, err := s.db.Exec(`DELETE FROM messages WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) CreateAccount(account Account) (Account, error) {
+	if account.Role == "" {
+		account.Role = "user"
+	}
+	err := s.db.QueryRow(
+		`INSERT INTO accounts (id, username, password_hash, email, avatar_url, role) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at::text`,
+		account.ID, account.Username, account.PasswordHash, account.Email, account.AvatarURL, account.Role,
+	).Scan(&account.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return Account{}, ErrAccountExists
+		}
+		return Account{}, err
+	}
+	return account, nil
+}
+
+func (s *postgresStore) GetAccount(id string) (Account, error) {
+	var account Account
+	err := s.db.QueryRow(
+		`SELECT id, username, password_hash, email, avatar_url, role, created_at::text FROM accounts WHERE id = $1`,
+		id,
+	).Scan(&account.ID, &account.Username, &account.PasswordHash, &account.Email, &account.AvatarURL, &account.Role, &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Account{}, ErrNotFound
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	return account, nil
+}
+
+func (s *postgresStore) GetAccountByUsername(username string) (Account, error) {
+	var account Account
+	err := s.db.QueryRow(
+		`SELECT id, username, password_hash, email, avatar_url, role, created_at::text FROM accounts WHERE username = $1`,
+		username,
+	).Scan(&account.ID, &account.Username, &account.PasswordHash, &account.Email, &account.AvatarURL, &account.Role, &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Account{}, ErrNotFound
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	return account, nil
+}
+
+func (s *postgresStore) CreateRoom(room Room) (Room, error) {
+	err := s.db.QueryRow(
+		`INSERT INTO rooms (id, name, owner_id) VALUES ($1, $2, $3) RETURNING created_at::text`,
+		room.ID, room.Name, room.OwnerID,
+	).Scan(&room.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return Room{}, ErrRoomExists
+		}
+		return Room{}, err
+	}
+	return room, nil
+}
+
+func (s *postgresStore) GetRoom(id string) (Room, error) {
+	var room Room
+	err := s.db.QueryRow(
+		`SELECT id, name, owner_id, created_at::text FROM rooms WHERE id = $1`, id,
+	).Scan(&room.ID, &room.Name, &room.OwnerID, &room.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Room{}, ErrNotFound
+	}
+	if err != nil {
+		return Room{}, err
+	}
+	return room, nil
+}
+
+func (s *postgresStore) ListRooms() ([]Room, error) {
+	rows, err := s.db.Query(`SELECT id, name, owner_id, created_at::text FROM rooms ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []Room
+	for rows.Next() {
+		var room Room
+		if err := rows.Scan(&room.ID, &room.Name, &room.OwnerID, &room.CreatedAt); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+func (s *postgresStore) Subscribe(roomID, clientID string) error {
+	_, err := s.db.Exec(
+		`INSERT INTO subscriptions (room_id, client_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		roomID, clientID,
+	)
+	return err
+}
+
+func (s *postgresStore) Unsubscribe(roomID, clientID string) error {
+	result, err := s.db.Exec(`DELETE FROM subscriptions WHERE room_id = $1 AND client_id = $2`, roomID, clientID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) IsSubscribed(roomID, clientID string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM subscriptions WHERE room_id = $1 AND client_id = $2)`, roomID, clientID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (s *postgresStore) ListSubscribers(roomID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT client_id FROM subscriptions WHERE room_id = $1`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clientIDs []string
+	for rows.Next() {
+		var clientID string
+		if err := rows.Scan(&clientID); err != nil {
+			return nil, err
+		}
+		clientIDs = append(clientIDs, clientID)
+	}
+	return clientIDs, rows.Err()
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,624 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteStore is the original single-file SQLite-backed Store.
+type sqliteStore struct {
+	db *sql.DB
+
+	// ftsEnabled is true once messages_fts has been created
+	// successfully. The mattn/go-sqlite3 driver only compiles in FTS5
+	// support when built with the "sqlite_fts5" (or "fts5") tag, so a
+	// binary built without it falls back to a LIKE-based Search
+	// instead of failing to start.
+	ftsEnabled bool
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sqliteStore{db: db}
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sqliteStore) init() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS users (
+			id TEXT PRIMARY KEY
+		);`,
+		`CREATE TABLE IF NOT EXISTS messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			client_id TEXT,
+			message TEXT,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages (timestamp);`,
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	// SQLite's ALTER TABLE ADD COLUMN has no IF NOT EXISTS clause, so
+	// columns added after the original schema have to be checked for
+	// by hand before altering the table.
+	if err := s.addColumnIfNotExists("messages", "room_id", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfNotExists("messages", "visible", "INTEGER DEFAULT 1"); err != nil {
+		return err
+	}
+
+	remainingStmts := []string{
+		`CREATE INDEX IF NOT EXISTS idx_messages_room_id ON messages (room_id);`,
+		`CREATE TABLE IF NOT EXISTS accounts (
+			id TEXT PRIMARY KEY,
+			username TEXT UNIQUE,
+			password_hash TEXT,
+			email TEXT,
+			avatar_url TEXT,
+			role TEXT DEFAULT 'user',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS rooms (
+			id TEXT PRIMARY KEY,
+			name TEXT UNIQUE,
+			owner_id TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS subscriptions (
+			room_id TEXT,
+			client_id TEXT,
+			joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (room_id, client_id)
+		);`,
+	}
+	for _, stmt := range remainingStmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if err := s.initFTS(); err != nil {
+		log.Printf("sqlite: FTS5 unavailable, falling back to LIKE search: %v", err)
+		return nil
+	}
+	s.ftsEnabled = true
+	return nil
+}
+
+// initFTS creates the messages_fts index and the triggers that keep it
+// in sync with the messages table. The mattn/go-sqlite3 driver only
+// compiles FTS5 support in when built with the "sqlite_fts5" (or
+// "fts5") tag, so this is allowed to fail: init() treats it as
+// non-fatal and Search falls back to a plain LIKE scan instead.
+func (s *sqliteStore) initFTS() error {
+	stmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+			message, content='messages', content_rowid='id'
+		);`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts(rowid, message) VALUES (new.id, new.message);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, message) VALUES ('delete', old.id, old.message);
+			INSERT INTO messages_fts(rowid, message) VALUES (new.id, new.message);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, message) VALUES ('delete', old.id, old.message);
+		END;`,
+		`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild');`,
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumnIfNotExists adds column to table with the given type/default
+// definition, unless it's already present. SQLite's ALTER TABLE ADD
+// COLUMN grammar has no IF NOT EXISTS clause, so existence is checked
+// by hand via PRAGMA table_info before running a plain ALTER TABLE.
+func (s *sqliteStore) addColumnIfNotExists(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dfltValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+func (s *sqliteStore) CreateUser(id string) error {
+	_, err := s.db.Exec(`INSERT INTO users (id) VALUES (?)`, id)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrConstraint {
+			return ErrUserExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *sqliteStore) DeleteUser(id string) error {
+	result, err := s.db.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteStore) UserExists(id string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)`, id).Scan(&exists)
+	return exists, err
+}
+
+func (s *sqliteStore) AddMessage(roomID, clientID, contents string) (Message, error) {
+	res, err := s.db.Exec(`INSERT INTO messages (room_id, client_id, message) VALUES (?, ?, ?)`, roomID, clientID, contents)
+	if err != nil {
+		return Message{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Message{}, err
+	}
+
+	var timestamp string
+	if err := s.db.QueryRow(`SELECT timestamp FROM messages WHERE id = ?`, id).Scan(&timestamp); err != nil {
+		return Message{}, err
+	}
+
+	return Message{ID: id, RoomID: roomID, ClientID: clientID, Contents: contents, Timestamp: timestamp, Visible: true}, nil
+}
+
+func (s *sqliteStore) InsertMessage(msg Message) (Message, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO messages (room_id, client_id, message, timestamp, visible) VALUES (?, ?, ?, ?, ?)`,
+		msg.RoomID, msg.ClientID, msg.Contents, msg.Timestamp, msg.Visible,
+	)
+	if err != nil {
+		return Message{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Message{}, err
+	}
+	msg.ID = id
+	return msg, nil
+}
+
+func (s *sqliteStore) ListMessages(filter Filter) ([]Message, error) {
+	query := `SELECT id, room_id, client_id, message, timestamp, visible FROM messages WHERE 1=1`
+	var args []interface{}
+
+	if filter.RoomID != "" {
+		query += ` AND room_id = ?`
+		args = append(args, filter.RoomID)
+	}
+	if filter.Before != "" {
+		query += ` AND timestamp < ?`
+		args = append(args, filter.Before)
+	}
+	if filter.After != "" {
+		query += ` AND timestamp > ?`
+		args = append(args, filter.After)
+	}
+	if filter.BeforeID > 0 {
+		query += ` AND id < ?`
+		args = append(args, filter.BeforeID)
+	}
+	if filter.AfterID > 0 {
+		query += ` AND id > ?`
+		args = append(args, filter.AfterID)
+	}
+	if !filter.IncludeHidden {
+		query += ` AND visible = 1`
+	}
+	ascending := ascendingPagination(filter)
+	if ascending {
+		query += ` ORDER BY timestamp ASC, id ASC`
+	} else {
+		query += ` ORDER BY timestamp DESC, id DESC`
+	}
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.RoomID, &msg.ClientID, &msg.Contents, &msg.Timestamp, &msg.Visible); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !ascending {
+		reverseMessages(messages)
+	}
+	return messages, nil
+}
+
+// Search runs filter.Query against the messages_fts FTS5 index, which
+// is kept in sync with the messages table by triggers created in
+// initFTS(). Results are ordered by FTS5 rank and excerpted with
+// snippet(). Falls back to searchLike when the binary wasn't built
+// with FTS5 support.
+func (s *sqliteStore) Search(filter SearchFilter) ([]SearchResult, error) {
+	if !s.ftsEnabled {
+		return s.searchLike(filter)
+	}
+
+	query := `SELECT m.id, m.room_id, m.client_id, m.message, m.timestamp,
+		snippet(messages_fts, 0, '<mark>', '</mark>', '...', 10)
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		WHERE messages_fts MATCH ? AND m.visible = 1`
+	args := []interface{}{filter.Query}
+
+	if filter.RoomID != "" {
+		query += ` AND m.room_id = ?`
+		args = append(args, filter.RoomID)
+	}
+	if filter.ClientID != "" {
+		query += ` AND m.client_id = ?`
+		args = append(args, filter.ClientID)
+	}
+	if filter.From != "" {
+		query += ` AND m.timestamp >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += ` AND m.timestamp <= ?`
+		args = append(args, filter.To)
+	}
+	query += ` ORDER BY rank`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += ` OFFSET ?`
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var res SearchResult
+		if err := rows.Scan(&res.ID, &res.RoomID, &res.ClientID, &res.Contents, &res.Timestamp, &res.Snippet); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// searchLike is the LIKE-based fallback used by Search when the
+// messages_fts index couldn't be created, mirroring the postgres
+// backend's ILIKE scan since neither has a native snippet() function
+// to rely on.
+func (s *sqliteStore) searchLike(filter SearchFilter) ([]SearchResult, error) {
+	query := `SELECT id, room_id, client_id, message, timestamp FROM messages WHERE message LIKE ? ESCAPE '\' AND visible = 1`
+	args := []interface{}{"%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(filter.Query) + "%"}
+
+	if filter.RoomID != "" {
+		query += ` AND room_id = ?`
+		args = append(args, filter.RoomID)
+	}
+	if filter.ClientID != "" {
+		query += ` AND client_id = ?`
+		args = append(args, filter.ClientID)
+	}
+	if filter.From != "" {
+		query += ` AND timestamp >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += ` AND timestamp <= ?`
+		args = append(args, filter.To)
+	}
+	query += ` ORDER BY timestamp DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += ` OFFSET ?`
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lowerQuery := strings.ToLower(filter.Query)
+	var results []SearchResult
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.RoomID, &msg.ClientID, &msg.Contents, &msg.Timestamp); err != nil {
+			return nil, err
+		}
+		idx := strings.Index(strings.ToLower(msg.Contents), lowerQuery)
+		results = append(results, SearchResult{
+			ID:        msg.ID,
+			RoomID:    msg.RoomID,
+			ClientID:  msg.ClientID,
+			Contents:  msg.Contents,
+			Timestamp: msg.Timestamp,
+			Snippet:   snippetAround(msg.Contents, idx, len(filter.Query)),
+		})
+	}
+	return results, rows.Err()
+}
+
+func (s *sqliteStore) GetMessage(id int64) (Message, error) {
+	var msg Message
+	err := s.db.QueryRow(
+		`SELECT id, room_id, client_id, message, timestamp, visible FROM messages WHERE id = ?`, id,
+	).Scan(&msg.ID, &msg.RoomID, &msg.ClientID, &msg.Contents, &msg.Timestamp, &msg.Visible)
+	if err == sql.ErrNoRows {
+		return Message{}, ErrNotFound
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func (s *sqliteStore) UpdateMessageContents(id int64, contents string) (Message, error) {
+	result, err := s.db.Exec(`UPDATE messages SET message = ? WHERE id = ?`, contents, id)
+	if err != nil {
+		return Message{}, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Message{}, err
+	}
+	if rowsAffected == 0 {
+		return Message{}, ErrNotFound
+	}
+	return s.GetMessage(id)
+}
+
+func (s *sqliteStore) HideMessage(id int64) error {
+	result, err := s.db.Exec(`UPDATE messages SET visible = 0 WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteStore) DeleteMessage(id int64) error {
+	result, err := s.db.Exec(`DELETE FROM messages WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteStore) CreateAccount(account Account) (Account, error) {
+	if account.Role == "" {
+		account.Role = "user"
+	}
+	err := s.db.QueryRow(
+		`INSERT INTO accounts (id, username, password_hash, email, avatar_url, role) VALUES (?, ?, ?, ?, ?, ?) RETURNING created_at`,
+		account.ID, account.Username, account.PasswordHash, account.Email, account.AvatarURL, account.Role,
+	).Scan(&account.CreatedAt)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrConstraint {
+			return Account{}, ErrAccountExists
+		}
+		return Account{}, err
+	}
+	return account, nil
+}
+
+func (s *sqliteStore) GetAccount(id string) (Account, error) {
+	var account Account
+	err := s.db.QueryRow(
+		`SELECT id, username, password_hash, email, avatar_url, role, created_at FROM accounts WHERE id = ?`,
+		id,
+	).Scan(&account.ID, &account.Username, &account.PasswordHash, &account.Email, &account.AvatarURL, &account.Role, &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Account{}, ErrNotFound
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	return account, nil
+}
+
+func (s *sqliteStore) GetAccountByUsername(username string) (Account, error) {
+	var account Account
+	err := s.db.QueryRow(
+		`SELECT id, username, password_hash, email, avatar_url, role, created_at FROM accounts WHERE username = ?`,
+		username,
+	).Scan(&account.ID, &account.Username, &account.PasswordHash, &account.Email, &account.AvatarURL, &account.Role, &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Account{}, ErrNotFound
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	return account, nil
+}
+
+func (s *sqliteStore) CreateRoom(room Room) (Room, error) {
+	err := s.db.QueryRow(
+		`INSERT INTO rooms (id, name, owner_id) VALUES (?, ?, ?) RETURNING created_at`,
+		room.ID, room.Name, room.OwnerID,
+	).Scan(&room.CreatedAt)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrConstraint {
+			return Room{}, ErrRoomExists
+		}
+		return Room{}, err
+	}
+	return room, nil
+}
+
+func (s *sqliteStore) GetRoom(id string) (Room, error) {
+	var room Room
+	err := s.db.QueryRow(
+		`SELECT id, name, owner_id, created_at FROM rooms WHERE id = ?`, id,
+	).Scan(&room.ID, &room.Name, &room.OwnerID, &room.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Room{}, ErrNotFound
+	}
+	if err != nil {
+		return Room{}, err
+	}
+	return room, nil
+}
+
+func (s *sqliteStore) ListRooms() ([]Room, error) {
+	rows, err := s.db.Query(`SELECT id, name, owner_id, created_at FROM rooms ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []Room
+	for rows.Next() {
+		var room Room
+		if err := rows.Scan(&room.ID, &room.Name, &room.OwnerID, &room.CreatedAt); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+func (s *sqliteStore) Subscribe(roomID, clientID string) error {
+	_, err := s.db.Exec(`INSERT OR IGNORE INTO subscriptions (room_id, client_id) VALUES (?, ?)`, roomID, clientID)
+	return err
+}
+
+func (s *sqliteStore) Unsubscribe(roomID, clientID string) error {
+	result, err := s.db.Exec(`DELETE FROM subscriptions WHERE room_id = ? AND client_id = ?`, roomID, clientID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteStore) IsSubscribed(roomID, clientID string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM subscriptions WHERE room_id = ? AND client_id = ?)`, roomID, clientID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (s *sqliteStore) ListSubscribers(roomID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT client_id FROM subscriptions WHERE room_id = ?`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clientIDs []string
+	for rows.Next() {
+		var clientID string
+		if err := rows.Scan(&clientID); err != nil {
+			return nil, err
+		}
+		clientIDs = append(clientIDs, clientID)
+	}
+	return clientIDs, rows.Err()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// reverseMessages flips a DESC-ordered slice back into chronological
+// order. Shared by the sqlite3 and postgres stores, which both page
+// newest-first so LIMIT keeps the most recent rows.
+func reverseMessages(messages []Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
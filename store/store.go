@@ -0,0 +1,195 @@
+// Package store defines the Store persistence boundary used by
+// the chat server, with memory, sqlite3, and postgres backends.
+package store
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUserExists is returned by CreateUser when the client ID is already
+// registered.
+var ErrUserExists = errors.New("client ID already exists")
+
+// ErrNotFound is returned when a user, message, room, or subscription
+// lookup/deletion does not match any row.
+var ErrNotFound = errors.New("not found")
+
+// ErrAccountExists is returned by CreateAccount when the username is
+// already registered.
+var ErrAccountExists = errors.New("username already exists")
+
+// ErrRoomExists is returned by CreateRoom when the room name is
+// already taken.
+var ErrRoomExists = errors.New("room already exists")
+
+// Message is a single chat message as stored by a Store and as
+// exchanged over the WebSocket stream. Visible is false once a message
+// has been hidden by its author or a moderator; hidden messages are
+// excluded from ListMessages unless Filter.IncludeHidden is set.
+type Message struct {
+	ID        int64  `json:"id,omitempty"`
+	RoomID    string `json:"room_id"`
+	ClientID  string `json:"client_id"`
+	Contents  string `json:"contents"`
+	Timestamp string `json:"timestamp"`
+	Visible   bool   `json:"visible"`
+}
+
+// Account is a registered login identity: a username/password pair
+// bound to the client ID used everywhere else in the store. Role is
+// "user" for ordinary accounts and "admin" for accounts allowed to
+// moderate other clients' messages.
+type Account struct {
+	ID           string `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Email        string `json:"email,omitempty"`
+	AvatarURL    string `json:"avatar_url,omitempty"`
+	Role         string `json:"role"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Room is a named channel that clients subscribe to and send messages
+// within.
+type Room struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	OwnerID   string `json:"owner_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// SearchResult is one match from Store.Search: the message plus a
+// short excerpt highlighting where the query matched.
+type SearchResult struct {
+	ID        int64  `json:"id"`
+	RoomID    string `json:"room_id"`
+	ClientID  string `json:"client_id"`
+	Contents  string `json:"contents"`
+	Timestamp string `json:"timestamp"`
+	Snippet   string `json:"snippet"`
+}
+
+// SearchFilter narrows a Store.Search call. Query is required; the
+// rest scope and paginate the results. As with Filter, a zero Limit
+// means "no limit"; searchHandler always passes a positive one (50 by
+// default).
+type SearchFilter struct {
+	Query    string
+	RoomID   string
+	ClientID string
+	From     string
+	To       string
+	Offset   int
+	Limit    int
+}
+
+// snippetAround returns a short excerpt of text centered on a match of
+// the given length starting at idx, for backends (memory, postgres)
+// that don't have a native full-text snippet function like SQLite
+// FTS5's snippet().
+func snippetAround(text string, idx, matchLen int) string {
+	const context = 30
+	start := idx - context
+	if start < 0 {
+		start = 0
+	}
+	end := idx + matchLen + context
+	if end > len(text) {
+		end = len(text)
+	}
+
+	snippet := text[start:end]
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(text) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// Filter narrows a ListMessages call. A zero value Limit means "no
+// limit" — return every matching row. BeforeID/AfterID support
+// id-based cursor pagination, immune to timestamps colliding at
+// whole-second resolution (used by ?before_id= and by
+// contrib/migrate); Before/After support the CHATHISTORY-style
+// timestamp window used by the WebSocket backlog replay. RoomID
+// scopes the query to a single room. IncludeHidden disables the
+// default visible-only filtering, for moderators reviewing hidden
+// messages. Ascending asks for the oldest Limit matching rows instead
+// of the default newest-first page — set by callers resuming forward
+// from a cursor (contrib/migrate, WebSocket backlog resync), since a
+// zero-valued After/AfterID can't be told apart from "no cursor set".
+type Filter struct {
+	RoomID        string
+	Before        string
+	After         string
+	BeforeID      int64
+	AfterID       int64
+	Limit         int
+	IncludeHidden bool
+	Ascending     bool
+}
+
+// ascendingPagination reports whether filter should be paged oldest
+// first: ORDER BY ... ASC ... LIMIT directly, instead of the default
+// ORDER BY ... DESC ... LIMIT then reverse (which always returns the
+// newest Limit rows, not the next Limit rows after a cursor).
+func ascendingPagination(filter Filter) bool {
+	return filter.Ascending
+}
+
+// Store is the persistence boundary for users, accounts, rooms, and
+// messages. It is implemented by the memory, sqlite3, and postgres
+// backends so that handlers and tests don't depend on a concrete
+// database.
+type Store interface {
+	CreateUser(id string) error
+	DeleteUser(id string) error
+	UserExists(id string) (bool, error)
+
+	AddMessage(roomID, clientID, contents string) (Message, error)
+	// InsertMessage writes msg as-is, preserving its Timestamp and
+	// Visible fields instead of stamping "now" and defaulting to
+	// visible like AddMessage does. ID is ignored and reassigned by
+	// the backend. Intended for contrib/migrate, which needs to
+	// reproduce history fidelity, not just message content.
+	InsertMessage(msg Message) (Message, error)
+	ListMessages(filter Filter) ([]Message, error)
+	Search(filter SearchFilter) ([]SearchResult, error)
+	GetMessage(id int64) (Message, error)
+	UpdateMessageContents(id int64, contents string) (Message, error)
+	HideMessage(id int64) error
+	DeleteMessage(id int64) error
+
+	CreateAccount(account Account) (Account, error)
+	GetAccount(id string) (Account, error)
+	GetAccountByUsername(username string) (Account, error)
+
+	CreateRoom(room Room) (Room, error)
+	GetRoom(id string) (Room, error)
+	ListRooms() ([]Room, error)
+
+	Subscribe(roomID, clientID string) error
+	Unsubscribe(roomID, clientID string) error
+	IsSubscribed(roomID, clientID string) (bool, error)
+	ListSubscribers(roomID string) ([]string, error)
+
+	Close() error
+}
+
+// Open builds a Store from a -store flag value: "memory",
+// "sqlite3:<path>", or a "postgres://" / "postgresql://" DSN.
+func Open(dsn string) (Store, error) {
+	switch {
+	case dsn == "memory":
+		return newMemoryStore(), nil
+	case strings.HasPrefix(dsn, "sqlite3:"):
+		return newSQLiteStore(strings.TrimPrefix(dsn, "sqlite3:"))
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return newPostgresStore(dsn)
+	default:
+		return nil, errors.New("unrecognized store dsn: " + dsn)
+	}
+}
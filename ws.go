@@ -0,0 +1,156 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/hariharan333/chatapp/store"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// wsHandler upgrades the connection to a WebSocket, replays missed
+// backlog CHATHISTORY-style (before/after/limit query params), then
+// streams the target room's broadcasts to the client and
+// stores+broadcasts any inbound {contents, timestamp} messages it
+// sends. The room defaults to generalRoomID; a ?room= param joins any
+// other room the caller is already subscribed to.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	clientID := accountIDFromContext(r.Context())
+
+	roomID := r.URL.Query().Get("room")
+	if roomID == "" {
+		roomID = generalRoomID
+	}
+
+	exists, err := msgStore.UserExists(clientID)
+	if err != nil || !exists {
+		http.Error(w, "Invalid client ID: Access Denied", http.StatusUnauthorized)
+		return
+	}
+
+	if roomID != generalRoomID {
+		subscribed, err := msgStore.IsSubscribed(roomID, clientID)
+		if err != nil || !subscribed {
+			http.Error(w, "Not subscribed to room", http.StatusForbidden)
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("WebSocket upgrade failed:", err)
+		return
+	}
+	defer conn.Close()
+
+	client := &Client{
+		ID:      clientID,
+		MsgChan: make(chan roomBroadcast, 10),
+	}
+
+	hub.join(roomID, client)
+
+	// leave is called from two places: explicitly below, before
+	// closing MsgChan, and from this defer to cover the early-return
+	// backlog-replay paths below. sync.Once makes it fire exactly once
+	// per connection. It still calls leaveIfCurrent rather than leave,
+	// though: even a single-fire cleanup can race a fast reconnect
+	// under the same client ID, and an unconditional delete would
+	// evict the newer connection's live hub entry instead of being a
+	// no-op, since hub.rooms is keyed by client ID rather than by
+	// connection. leaveIfCurrent only removes the entry if it still
+	// points at this connection's *Client.
+	var leaveOnce sync.Once
+	leave := func() { leaveOnce.Do(func() { hub.leaveIfCurrent(roomID, client) }) }
+	defer leave()
+
+	backlog, err := msgStore.ListMessages(backlogFilter(r.URL.Query(), roomID))
+	if err != nil {
+		log.Println("Failed to load backlog for", clientID, ":", err)
+	}
+	for _, msg := range backlog {
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range client.MsgChan {
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var in struct {
+			Contents  string `json:"contents"`
+			Timestamp string `json:"timestamp"`
+		}
+		if err := conn.ReadJSON(&in); err != nil {
+			break
+		}
+		if in.Contents == "" {
+			continue
+		}
+		if allowed, retryAfter := messageLimiter.allow(clientID); !allowed {
+			conn.WriteJSON(map[string]interface{}{"error": "rate limit exceeded", "retry_after": retryAfter.Seconds()})
+			continue
+		}
+
+		msg, err := msgStore.AddMessage(roomID, clientID, in.Contents)
+		if err != nil {
+			log.Println("Failed to store message from", clientID, ":", err)
+			continue
+		}
+
+		hub.broadcast <- roomBroadcast{RoomID: roomID, Event: eventMessageCreated, Msg: msg}
+	}
+
+	// Unregister before closing MsgChan: hub.start() can still be
+	// selecting on it to broadcast, and sending to a closed channel
+	// panics. Once leave() returns, the hub holds no reference to
+	// client and nothing else will send on MsgChan, so it's safe to
+	// close — which lets the writer goroutine's range loop exit and
+	// done fire. Without this, the deferred leave()/conn.Close above
+	// would never run, leaking the goroutine and the hub entry.
+	leave()
+	close(client.MsgChan)
+	<-done
+}
+
+// backlogFilter turns the /ws query params into a store.Filter scoped
+// to roomID, IRC CHATHISTORY-style: before/after bound the timestamp
+// window and limit caps how many rows come back, defaulting to 50. An
+// after-only window (no before) means the client is resuming forward
+// from a cursor it already has, so that case pages oldest-first
+// instead of the usual newest-first — otherwise a client that missed
+// more than limit messages could never page past the most recent ones.
+func backlogFilter(q url.Values, roomID string) store.Filter {
+	after := q.Get("after")
+	before := q.Get("before")
+	filter := store.Filter{
+		RoomID:    roomID,
+		Before:    before,
+		After:     after,
+		Ascending: after != "" && before == "",
+		Limit:     50,
+	}
+	if l := q.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			filter.Limit = parsed
+		}
+	}
+	return filter
+}
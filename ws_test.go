@@ -0,0 +1,229 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hariharan333/chatapp/store"
+)
+
+// TestWSHandlerCleansUpOnDisconnect guards against the hub/goroutine
+// leak where closing the socket never unblocked the writer goroutine
+// waiting on client.MsgChan, so the deferred hub.leave/conn.Close
+// never ran.
+func TestWSHandlerCleansUpOnDisconnect(t *testing.T) {
+	var err error
+	msgStore, err = store.Open("memory")
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	if err := msgStore.CreateUser("alice"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", withAuth(wsHandler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	header := http.Header{}
+	header.Set("Cookie", sessionCookieName+"="+signSession("alice"))
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		hub.mu.Lock()
+		joined := hub.rooms[generalRoomID]["alice"] != nil
+		hub.mu.Unlock()
+		if joined {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("client never appeared in hub.rooms before close")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	conn.Close()
+
+	deadline = time.After(2 * time.Second)
+	for {
+		hub.mu.Lock()
+		_, stillPresent := hub.rooms[generalRoomID]["alice"]
+		hub.mu.Unlock()
+		if !stillPresent {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatal("hub.rooms still holds the client after disconnect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// TestWSHandlerReconnectSurvivesStaleCleanup guards against the old
+// double hub.leave call (one explicit, one deferred) racing a fast
+// reconnect: if the deferred call from a disconnected connection fired
+// after the same client ID had already rejoined, it deleted the new
+// connection's live hub entry instead of being a no-op.
+func TestWSHandlerReconnectSurvivesStaleCleanup(t *testing.T) {
+	var err error
+	msgStore, err = store.Open("memory")
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	if err := msgStore.CreateUser("dave"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", withAuth(wsHandler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	header := http.Header{}
+	header.Set("Cookie", sessionCookieName+"="+signSession("dave"))
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return conn
+	}
+	waitJoined := func() {
+		deadline := time.After(2 * time.Second)
+		for {
+			hub.mu.Lock()
+			joined := hub.rooms[generalRoomID]["dave"] != nil
+			hub.mu.Unlock()
+			if joined {
+				return
+			}
+			select {
+			case <-deadline:
+				t.Fatal("client never appeared in hub.rooms")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	first := dial()
+	waitJoined()
+	first.Close()
+
+	second := dial()
+	defer second.Close()
+	waitJoined()
+
+	// Give the first connection's writer goroutine and deferred
+	// cleanup time to fully unwind before asserting the second
+	// connection's hub entry wasn't stolen out from under it.
+	time.Sleep(200 * time.Millisecond)
+
+	hub.mu.Lock()
+	stillJoined := hub.rooms[generalRoomID]["dave"] != nil
+	hub.mu.Unlock()
+	if !stillJoined {
+		t.Fatal("reconnect's hub entry was removed by the first connection's stale cleanup")
+	}
+}
+
+// TestWSHandlerJoinsRequestedRoom guards against /ws only ever
+// joining generalRoomID: a subscribed client passing ?room= should be
+// registered in that room's hub entry, not general's.
+func TestWSHandlerJoinsRequestedRoom(t *testing.T) {
+	var err error
+	msgStore, err = store.Open("memory")
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	if err := msgStore.CreateUser("bob"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := msgStore.CreateRoom(store.Room{ID: "watercooler", Name: "watercooler", OwnerID: "bob"}); err != nil {
+		t.Fatalf("CreateRoom: %v", err)
+	}
+	if err := msgStore.Subscribe("watercooler", "bob"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", withAuth(wsHandler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?room=watercooler"
+	header := http.Header{}
+	header.Set("Cookie", sessionCookieName+"="+signSession("bob"))
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		hub.mu.Lock()
+		joinedRoom := hub.rooms["watercooler"]["bob"] != nil
+		joinedGeneral := hub.rooms[generalRoomID]["bob"] != nil
+		hub.mu.Unlock()
+		if joinedRoom {
+			if joinedGeneral {
+				t.Fatal("client joined both watercooler and general")
+			}
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatal("client never appeared in watercooler's hub entry")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// TestWSHandlerRejectsUnsubscribedRoom guards against joining a room
+// the caller was never subscribed to via ?room=.
+func TestWSHandlerRejectsUnsubscribedRoom(t *testing.T) {
+	var err error
+	msgStore, err = store.Open("memory")
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	if err := msgStore.CreateUser("carol"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := msgStore.CreateRoom(store.Room{ID: "private", Name: "private", OwnerID: "someone-else"}); err != nil {
+		t.Fatalf("CreateRoom: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", withAuth(wsHandler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?room=private"
+	header := http.Header{}
+	header.Set("Cookie", sessionCookieName+"="+signSession("carol"))
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err == nil {
+		t.Fatal("expected dial to fail for an unsubscribed room")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 Forbidden, got %v", resp)
+	}
+}